@@ -0,0 +1,97 @@
+package chunk
+
+import (
+	"context"
+	"io"
+	"os"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// CASHash is the hash type used to key the content-addressed pool.
+// sha256 is required here (unlike the looser per-upload CRC32/xxh64
+// checks) because refcounted dedup needs collision resistance.
+var CASHash = utils.SHA256
+
+// CASIndex tracks which content hashes the server already holds on disk
+// (under conf.TempDir/cas) and how many uploads currently reference each
+// one, so a blob can be safely removed once nothing points at it
+// anymore.
+type CASIndex interface {
+	// Path returns where the blob for hash would live on disk,
+	// regardless of whether it currently exists.
+	Path(hash string) string
+	// Has reports whether a complete blob is already stored for hash.
+	Has(ctx context.Context, hash string) (bool, error)
+	// Put adopts src as the blob for hash if none exists yet (moving it
+	// into the pool) and increments its refcount; if one already
+	// exists, src is discarded and the existing blob's refcount is
+	// bumped instead. Either way the caller's src no longer needs
+	// keeping.
+	Put(ctx context.Context, hash string, size int64, src string) error
+	// Retain increments the refcount for an existing blob, e.g. when a
+	// precheck determines an upload can reuse it without re-uploading.
+	Retain(ctx context.Context, hash string) error
+	// Release decrements hash's refcount and deletes the blob once it
+	// reaches zero.
+	Release(ctx context.Context, hash string) error
+}
+
+func casPath(hash string) string {
+	if len(hash) < 2 {
+		return stdpath.Join(conf.Conf.TempDir, "cas", "_", hash)
+	}
+	return stdpath.Join(conf.Conf.TempDir, "cas", hash[:2], hash)
+}
+
+// HashFile computes the CAS hash of an on-disk file without loading it
+// into memory.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return utils.HashReader(CASHash, f)
+}
+
+// ReleaseChunks drops session's reference on every chunk blob it
+// recorded in the CAS pool, e.g. when the session is aborted, expired,
+// or its merge has finished reading the chunks into the destination.
+// The blob itself is only deleted once its last referrer does this (see
+// CASIndex.Release).
+func ReleaseChunks(ctx context.Context, cas CASIndex, session *UploadSession) {
+	for _, cs := range session.Chunks {
+		if cs.Hash == "" {
+			continue
+		}
+		if err := cas.Release(ctx, cs.Hash); err != nil {
+			utils.Log.Warnf("[chunk] failed to release CAS blob %s: %v", cs.Hash, err)
+		}
+	}
+}
+
+// CopyIntoStaging streams r into a new temporary file under
+// conf.TempDir/cas/staging so it can be hashed and then adopted into the
+// pool by CASIndex.Put without a second copy on the happy path (Put
+// renames rather than copies whenever src and the pool live on the same
+// filesystem).
+func CopyIntoStaging(r io.Reader) (path string, size int64, err error) {
+	dir := stdpath.Join(conf.Conf.TempDir, "cas", "staging")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, err
+	}
+	f, err := os.CreateTemp(dir, "chunk-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	size, err = io.Copy(f, r)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", 0, err
+	}
+	return f.Name(), size, nil
+}
@@ -0,0 +1,95 @@
+package chunk
+
+import (
+	"context"
+	"os"
+	stdpath "path"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"gorm.io/gorm"
+)
+
+// casBlobPO is the refcount row for one content-addressed blob. The
+// blob's bytes live on disk at casPath(Hash); this table only tracks
+// how many uploads currently reference it.
+type casBlobPO struct {
+	Hash      string `gorm:"primaryKey"`
+	Size      int64
+	RefCount  int
+	CreatedAt time.Time
+}
+
+func (casBlobPO) TableName() string { return "x_cas_blobs" }
+
+// dbCASIndex is the CASIndex backed by the application database, used
+// whenever one is configured so dedup works across multiple instances
+// sharing the same DB and a shared TempDir (e.g. NFS-mounted).
+type dbCASIndex struct{}
+
+// NewDbCASIndex returns a CASIndex backed by db.Gorm().
+func NewDbCASIndex() CASIndex {
+	return &dbCASIndex{}
+}
+
+// AutoMigrateCAS creates/updates the CAS refcount table.
+func AutoMigrateCAS() error {
+	return db.Gorm().AutoMigrate(&casBlobPO{})
+}
+
+func (idx *dbCASIndex) Path(hash string) string {
+	return casPath(hash)
+}
+
+func (idx *dbCASIndex) Has(ctx context.Context, hash string) (bool, error) {
+	var count int64
+	err := db.Gorm().WithContext(ctx).Model(&casBlobPO{}).Where("hash = ?", hash).Count(&count).Error
+	return count > 0, err
+}
+
+func (idx *dbCASIndex) Put(ctx context.Context, hash string, size int64, src string) error {
+	dst := idx.Path(hash)
+	return db.Gorm().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing casBlobPO
+		err := tx.Where("hash = ?", hash).First(&existing).Error
+		if err == nil {
+			os.Remove(src)
+			return tx.Model(&casBlobPO{}).Where("hash = ?", hash).
+				Update("ref_count", gorm.Expr("ref_count + 1")).Error
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err := os.MkdirAll(stdpath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+		return tx.Create(&casBlobPO{Hash: hash, Size: size, RefCount: 1, CreatedAt: time.Now()}).Error
+	})
+}
+
+func (idx *dbCASIndex) Retain(ctx context.Context, hash string) error {
+	return db.Gorm().WithContext(ctx).Model(&casBlobPO{}).Where("hash = ?", hash).
+		Update("ref_count", gorm.Expr("ref_count + 1")).Error
+}
+
+func (idx *dbCASIndex) Release(ctx context.Context, hash string) error {
+	return db.Gorm().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&casBlobPO{}).Where("hash = ?", hash).
+			Update("ref_count", gorm.Expr("ref_count - 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		var blob casBlobPO
+		if err := tx.Where("hash = ?", hash).First(&blob).Error; err != nil {
+			return err
+		}
+		if blob.RefCount <= 0 {
+			os.Remove(idx.Path(hash))
+			return tx.Delete(&blob).Error
+		}
+		return nil
+	})
+}
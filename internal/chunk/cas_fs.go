@@ -0,0 +1,120 @@
+package chunk
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	stdpath "path"
+	"sync"
+)
+
+// fsCASIndex is the filesystem fallback for CASIndex: refcounts live in
+// a small JSON sidecar next to each blob. It's only safe for a single
+// process, which matches the rest of this package's fs fallback (see
+// fsStore) - multi-instance dedup needs the db-backed index.
+type fsCASIndex struct {
+	mu sync.Mutex
+}
+
+// NewFsCASIndex returns a CASIndex that keeps its refcounts as JSON
+// sidecars alongside the blobs themselves.
+func NewFsCASIndex() CASIndex {
+	return &fsCASIndex{}
+}
+
+type casRefcount struct {
+	Size     int64 `json:"size"`
+	RefCount int   `json:"ref_count"`
+}
+
+func (idx *fsCASIndex) Path(hash string) string {
+	return casPath(hash)
+}
+
+func (idx *fsCASIndex) sidecarPath(hash string) string {
+	return casPath(hash) + ".json"
+}
+
+func (idx *fsCASIndex) readRefcount(hash string) (*casRefcount, error) {
+	data, err := os.ReadFile(idx.sidecarPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	rc := &casRefcount{}
+	if err := json.Unmarshal(data, rc); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+func (idx *fsCASIndex) writeRefcount(hash string, rc *casRefcount) error {
+	data, err := json.Marshal(rc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(idx.sidecarPath(hash), data, 0644)
+}
+
+func (idx *fsCASIndex) Has(ctx context.Context, hash string) (bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	_, err := os.Stat(idx.Path(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (idx *fsCASIndex) Put(ctx context.Context, hash string, size int64, src string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	dst := idx.Path(hash)
+	if err := os.MkdirAll(stdpath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		// Already have this content; drop the incoming copy and just
+		// bump the refcount.
+		os.Remove(src)
+		return idx.bumpLocked(hash, size, 1)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+	return idx.writeRefcount(hash, &casRefcount{Size: size, RefCount: 1})
+}
+
+func (idx *fsCASIndex) Retain(ctx context.Context, hash string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.bumpLocked(hash, 0, 1)
+}
+
+func (idx *fsCASIndex) Release(ctx context.Context, hash string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	rc, err := idx.readRefcount(hash)
+	if err != nil {
+		return err
+	}
+	rc.RefCount--
+	if rc.RefCount <= 0 {
+		os.Remove(idx.Path(hash))
+		os.Remove(idx.sidecarPath(hash))
+		return nil
+	}
+	return idx.writeRefcount(hash, rc)
+}
+
+func (idx *fsCASIndex) bumpLocked(hash string, size int64, delta int) error {
+	rc, err := idx.readRefcount(hash)
+	if err != nil {
+		rc = &casRefcount{Size: size}
+	}
+	rc.RefCount += delta
+	return idx.writeRefcount(hash, rc)
+}
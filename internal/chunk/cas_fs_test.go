@@ -0,0 +1,43 @@
+package chunk
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+)
+
+// TestReleaseChunksDropsRefsToZero covers the lifecycle ReleaseChunks is
+// meant to guard: FsUploadAbort and Reconcile call it for sessions that
+// never reach a successful merge, and the blob must actually disappear
+// once its only referrer releases it - not be left with a stuck
+// positive refcount.
+func TestReleaseChunksDropsRefsToZero(t *testing.T) {
+	conf.Conf = &conf.Config{TempDir: t.TempDir()}
+	idx := NewFsCASIndex()
+	ctx := context.Background()
+
+	const hash = "abc123"
+	blobPath := idx.Path(hash)
+	if err := os.MkdirAll(blobPath[:len(blobPath)-len(hash)], 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	srcPath := blobPath + ".src"
+	if err := os.WriteFile(srcPath, []byte("payload"), 0644); err != nil {
+		t.Fatalf("write src: %v", err)
+	}
+	if err := idx.Put(ctx, hash, 7, srcPath); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if ok, err := idx.Has(ctx, hash); err != nil || !ok {
+		t.Fatalf("Has after Put = %v, %v; want true, nil", ok, err)
+	}
+
+	session := &UploadSession{Chunks: map[int]ChunkState{0: {Index: 0, Hash: hash}}}
+	ReleaseChunks(ctx, idx, session)
+
+	if ok, err := idx.Has(ctx, hash); err != nil || ok {
+		t.Fatalf("Has after ReleaseChunks = %v, %v; want false, nil", ok, err)
+	}
+}
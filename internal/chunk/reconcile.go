@@ -0,0 +1,45 @@
+package chunk
+
+import (
+	"context"
+	"os"
+	stdpath "path"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// Reconcile runs once at startup. It resets sessions that were caught
+// mid-merge when the process died (so the client's next FsChunkMerge
+// call redoes the merge instead of hanging forever), and deletes
+// sessions that expired while the server was down - releasing their CAS
+// chunk references so the blobs aren't leaked with a stuck refcount.
+func Reconcile(ctx context.Context, store Store, cas CASIndex) {
+	sessions, err := store.List(ctx)
+	if err != nil {
+		utils.Log.Warnf("[chunk] failed to list upload sessions during reconcile: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, s := range sessions {
+		switch {
+		case !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt):
+			utils.Log.Infof("[chunk] reconcile: dropping expired upload session %s", s.ID)
+			ReleaseChunks(ctx, cas, s)
+			removeChunkDir(s.ID)
+			_ = store.Delete(ctx, s.ID)
+		case s.Status == StatusMerging:
+			utils.Log.Infof("[chunk] reconcile: upload session %s was interrupted mid-merge, resetting to active", s.ID)
+			_ = store.SetStatus(ctx, s.ID, StatusActive)
+		case s.Status == StatusAborted:
+			ReleaseChunks(ctx, cas, s)
+			removeChunkDir(s.ID)
+			_ = store.Delete(ctx, s.ID)
+		}
+	}
+}
+
+func removeChunkDir(id string) {
+	_ = os.RemoveAll(stdpath.Join(conf.Conf.TempDir, "chunks", id))
+}
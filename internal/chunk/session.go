@@ -0,0 +1,68 @@
+// Package chunk manages the bookkeeping for multi-request chunked
+// uploads (FsChunkUpload/FsChunkMerge): which chunks a given upload has
+// received, their checksums, and the metadata needed to resume or clean
+// up an upload that outlives a single request.
+package chunk
+
+import "time"
+
+// Status is the lifecycle state of an UploadSession.
+type Status string
+
+const (
+	StatusActive  Status = "active"
+	StatusMerging Status = "merging"
+	StatusDone    Status = "done"
+	StatusAborted Status = "aborted"
+	StatusExpired Status = "expired"
+)
+
+// ChunkState records what the server has persisted for a single chunk.
+// Hash is the strong content hash (sha256) used to key the chunk's blob
+// in the content-addressed pool; the chunk's bytes don't live anywhere
+// else once it's been admitted into the CAS (see cas.go).
+type ChunkState struct {
+	Index      int       `json:"index"`
+	Offset     int64     `json:"offset"`
+	Size       int64     `json:"size"`
+	CRC32      string    `json:"crc32"`
+	Hash       string    `json:"hash,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// UploadSession is the durable record of one chunked upload. It holds
+// everything FsChunkUpload/FsChunkMerge need to resume after a restart:
+// who owns the upload, where it goes, and which chunks have landed.
+type UploadSession struct {
+	ID          string             `json:"id"`
+	UserID      uint               `json:"user_id"`
+	Dir         string             `json:"dir"`
+	Name        string             `json:"name"`
+	TotalSize   int64              `json:"total_size"`
+	TotalChunks int                `json:"total_chunks"`
+	Chunks      map[int]ChunkState `json:"chunks"`
+	Hashes      map[string]string  `json:"hashes"` // declared client hashes: md5/sha1/sha256/xxh64
+	AsTask      bool               `json:"as_task"`
+	Overwrite   bool               `json:"overwrite"`
+	Status      Status             `json:"status"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+}
+
+// MissingChunks returns the indexes in [0, TotalChunks) that have not
+// been received yet, in ascending order.
+func (s *UploadSession) MissingChunks() []int {
+	var missing []int
+	for i := 0; i < s.TotalChunks; i++ {
+		if _, ok := s.Chunks[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// Complete reports whether every chunk has been received.
+func (s *UploadSession) Complete() bool {
+	return len(s.Chunks) >= s.TotalChunks
+}
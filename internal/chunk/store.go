@@ -0,0 +1,34 @@
+package chunk
+
+import "context"
+
+// Store persists UploadSessions so that a chunked upload can be resumed
+// after the server restarts, or inspected from a different instance
+// sharing the same database.
+//
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Create registers a new upload session. ID must be unique.
+	Create(ctx context.Context, session *UploadSession) error
+	// Get returns the session for id, or ErrNotFound if it doesn't exist.
+	Get(ctx context.Context, id string) (*UploadSession, error)
+	// PutChunk records that chunk has been received for the given
+	// upload and persists the updated session.
+	PutChunk(ctx context.Context, id string, chunk ChunkState) (*UploadSession, error)
+	// SetStatus transitions the session to status.
+	SetStatus(ctx context.Context, id string, status Status) error
+	// Delete removes the session record. It does not touch any staged
+	// chunk files; callers are responsible for that.
+	Delete(ctx context.Context, id string) error
+	// List returns every session known to the store, active or not, so
+	// callers can reconcile on-disk state at startup.
+	List(ctx context.Context) ([]*UploadSession, error)
+}
+
+// ErrNotFound is returned by Store.Get when no session exists for the
+// requested id.
+var ErrNotFound = errNotFound{}
+
+type errNotFound struct{}
+
+func (errNotFound) Error() string { return "upload session not found" }
@@ -0,0 +1,221 @@
+package chunk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// uploadSessionPO is the gorm-mapped row for an UploadSession. Chunks and
+// Hashes are stored as JSON text rather than normalized tables: chunk
+// counts per upload are small and we only ever read/write a session as
+// a whole, so there's nothing to gain from joins.
+type uploadSessionPO struct {
+	ID          string `gorm:"primaryKey"`
+	UserID      uint
+	Dir         string
+	Name        string
+	TotalSize   int64
+	TotalChunks int
+	ChunksJSON  string `gorm:"type:text"`
+	HashesJSON  string `gorm:"type:text"`
+	AsTask      bool
+	Overwrite   bool
+	Status      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+func (uploadSessionPO) TableName() string { return "x_upload_sessions" }
+
+// dbStore persists UploadSessions through the application's main
+// database, so sessions survive restarts and are visible to every
+// instance sharing that database.
+type dbStore struct{}
+
+// NewDbStore returns a Store backed by db.Gorm(). Callers should fall
+// back to NewFsStore() if no database is configured.
+func NewDbStore() Store {
+	return &dbStore{}
+}
+
+// AutoMigrate creates/updates the upload_sessions table. Call this once
+// at startup alongside the rest of the application's migrations.
+func AutoMigrate() error {
+	return db.Gorm().AutoMigrate(&uploadSessionPO{})
+}
+
+func toPO(s *UploadSession) (*uploadSessionPO, error) {
+	chunksJSON, err := json.Marshal(s.Chunks)
+	if err != nil {
+		return nil, err
+	}
+	hashesJSON, err := json.Marshal(s.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	return &uploadSessionPO{
+		ID:          s.ID,
+		UserID:      s.UserID,
+		Dir:         s.Dir,
+		Name:        s.Name,
+		TotalSize:   s.TotalSize,
+		TotalChunks: s.TotalChunks,
+		ChunksJSON:  string(chunksJSON),
+		HashesJSON:  string(hashesJSON),
+		AsTask:      s.AsTask,
+		Overwrite:   s.Overwrite,
+		Status:      string(s.Status),
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+		ExpiresAt:   s.ExpiresAt,
+	}, nil
+}
+
+func fromPO(po *uploadSessionPO) (*UploadSession, error) {
+	chunks := make(map[int]ChunkState)
+	if po.ChunksJSON != "" {
+		if err := json.Unmarshal([]byte(po.ChunksJSON), &chunks); err != nil {
+			return nil, err
+		}
+	}
+	hashes := make(map[string]string)
+	if po.HashesJSON != "" {
+		if err := json.Unmarshal([]byte(po.HashesJSON), &hashes); err != nil {
+			return nil, err
+		}
+	}
+	return &UploadSession{
+		ID:          po.ID,
+		UserID:      po.UserID,
+		Dir:         po.Dir,
+		Name:        po.Name,
+		TotalSize:   po.TotalSize,
+		TotalChunks: po.TotalChunks,
+		Chunks:      chunks,
+		Hashes:      hashes,
+		AsTask:      po.AsTask,
+		Overwrite:   po.Overwrite,
+		Status:      Status(po.Status),
+		CreatedAt:   po.CreatedAt,
+		UpdatedAt:   po.UpdatedAt,
+		ExpiresAt:   po.ExpiresAt,
+	}, nil
+}
+
+func (st *dbStore) Create(ctx context.Context, session *UploadSession) error {
+	session.CreatedAt = time.Now()
+	session.UpdatedAt = session.CreatedAt
+	if session.Status == "" {
+		session.Status = StatusActive
+	}
+	if session.Chunks == nil {
+		session.Chunks = make(map[int]ChunkState)
+	}
+	po, err := toPO(session)
+	if err != nil {
+		return err
+	}
+	// recordChunk/FsChunkPrecheck both Get-then-Create on ErrNotFound, so
+	// two chunk POSTs racing on a brand-new upload_id can both decide to
+	// create the session. Treat "it's already there" as success rather
+	// than surfacing a primary-key conflict: whichever request created it
+	// first wins, and PutChunk below merges in either case.
+	err = db.Gorm().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing uploadSessionPO
+		if err := tx.Where("id = ?", session.ID).First(&existing).Error; err == nil {
+			return nil
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		return tx.Create(po).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil
+	}
+	return err
+}
+
+func (st *dbStore) Get(ctx context.Context, id string) (*UploadSession, error) {
+	var po uploadSessionPO
+	err := db.Gorm().WithContext(ctx).Where("id = ?", id).First(&po).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromPO(&po)
+}
+
+func (st *dbStore) PutChunk(ctx context.Context, id string, chunk ChunkState) (*UploadSession, error) {
+	var session *UploadSession
+	err := db.Gorm().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Chunks for the same upload_id arrive concurrently (clients
+		// upload in parallel), so the read-merge-write below has to run
+		// under a row lock: without it, two chunk POSTs both read the
+		// same ChunksJSON, each add their own index in memory, and the
+		// later Updates silently clobbers the earlier chunk - the merge
+		// itself leaking a CAS ref and leaving the upload stuck on a
+		// chunk the server already has.
+		var po uploadSessionPO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", id).First(&po).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrNotFound
+			}
+			return err
+		}
+		s, err := fromPO(&po)
+		if err != nil {
+			return err
+		}
+		s.Chunks[chunk.Index] = chunk
+		s.UpdatedAt = time.Now()
+		newPO, err := toPO(s)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&uploadSessionPO{}).Where("id = ?", id).
+			Updates(map[string]any{"chunks_json": newPO.ChunksJSON, "updated_at": newPO.UpdatedAt}).Error; err != nil {
+			return err
+		}
+		session = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (st *dbStore) SetStatus(ctx context.Context, id string, status Status) error {
+	return db.Gorm().WithContext(ctx).Model(&uploadSessionPO{}).Where("id = ?", id).
+		Updates(map[string]any{"status": string(status), "updated_at": time.Now()}).Error
+}
+
+func (st *dbStore) Delete(ctx context.Context, id string) error {
+	return db.Gorm().WithContext(ctx).Where("id = ?", id).Delete(&uploadSessionPO{}).Error
+}
+
+func (st *dbStore) List(ctx context.Context) ([]*UploadSession, error) {
+	var pos []uploadSessionPO
+	if err := db.Gorm().WithContext(ctx).Find(&pos).Error; err != nil {
+		return nil, err
+	}
+	sessions := make([]*UploadSession, 0, len(pos))
+	for i := range pos {
+		s, err := fromPO(&pos[i])
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,150 @@
+package chunk
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+)
+
+// fsStore is the filesystem fallback used when no database is
+// configured. Each session is a single JSON manifest next to the chunk
+// files it describes, so reconciliation only has to walk one directory
+// tree at startup.
+type fsStore struct {
+	mu    sync.Mutex
+	cache map[string]*UploadSession
+}
+
+// NewFsStore returns a Store that keeps its manifests under
+// conf.Conf.TempDir/chunks/<id>/session.json.
+func NewFsStore() Store {
+	return &fsStore{cache: make(map[string]*UploadSession)}
+}
+
+func (st *fsStore) manifestPath(id string) string {
+	return stdpath.Join(conf.Conf.TempDir, "chunks", id, "session.json")
+}
+
+func (st *fsStore) Create(ctx context.Context, session *UploadSession) error {
+	session.CreatedAt = time.Now()
+	session.UpdatedAt = session.CreatedAt
+	if session.Status == "" {
+		session.Status = StatusActive
+	}
+	if session.Chunks == nil {
+		session.Chunks = make(map[int]ChunkState)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.persistLocked(session)
+}
+
+func (st *fsStore) Get(ctx context.Context, id string) (*UploadSession, error) {
+	st.mu.Lock()
+	if s, ok := st.cache[id]; ok {
+		st.mu.Unlock()
+		return s, nil
+	}
+	st.mu.Unlock()
+
+	data, err := os.ReadFile(st.manifestPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	session := &UploadSession{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, err
+	}
+	st.mu.Lock()
+	st.cache[id] = session
+	st.mu.Unlock()
+	return session, nil
+}
+
+func (st *fsStore) PutChunk(ctx context.Context, id string, chunk ChunkState) (*UploadSession, error) {
+	session, err := st.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	session.Chunks[chunk.Index] = chunk
+	session.UpdatedAt = time.Now()
+	if err := st.persistLocked(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (st *fsStore) SetStatus(ctx context.Context, id string, status Status) error {
+	session, err := st.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	session.Status = status
+	session.UpdatedAt = time.Now()
+	return st.persistLocked(session)
+}
+
+func (st *fsStore) Delete(ctx context.Context, id string) error {
+	st.mu.Lock()
+	delete(st.cache, id)
+	st.mu.Unlock()
+	err := os.Remove(st.manifestPath(id))
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (st *fsStore) List(ctx context.Context) ([]*UploadSession, error) {
+	root := stdpath.Join(conf.Conf.TempDir, "chunks")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sessions []*UploadSession
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		s, err := st.Get(ctx, e.Name())
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// persistLocked marshals session and writes its manifest to disk. The
+// marshal has to happen under st.mu, not just the cache update: Get
+// hands out the same *UploadSession pointer to every caller, so
+// marshaling it concurrently with another goroutine's in-place mutation
+// (e.g. PutChunk writing session.Chunks) is a data race on the Chunks
+// map. Callers must hold st.mu.
+func (st *fsStore) persistLocked(session *UploadSession) error {
+	dir := stdpath.Join(conf.Conf.TempDir, "chunks", session.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	st.cache[session.ID] = session
+	return os.WriteFile(st.manifestPath(session.ID), data, 0644)
+}
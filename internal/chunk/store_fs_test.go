@@ -0,0 +1,48 @@
+package chunk
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+)
+
+// TestFsStorePutChunkConcurrent exercises the normal case for a
+// multi-chunk upload: several PutChunk calls for different indexes of
+// the same session landing at once. Before persistLocked held the lock
+// across json.Marshal, this raced a marshal read of session.Chunks in
+// one goroutine against another goroutine's map write and crashed under
+// -race (and, in production, under the runtime's own concurrent map
+// read/write detector).
+func TestFsStorePutChunkConcurrent(t *testing.T) {
+	conf.Conf = &conf.Config{TempDir: t.TempDir()}
+	st := NewFsStore()
+	ctx := context.Background()
+
+	const id = "upload-1"
+	const chunks = 32
+	if err := st.Create(ctx, &UploadSession{ID: id, TotalChunks: chunks}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < chunks; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			if _, err := st.PutChunk(ctx, id, ChunkState{Index: index, Size: int64(index)}); err != nil {
+				t.Errorf("PutChunk(%d): %v", index, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	session, err := st.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(session.Chunks) != chunks {
+		t.Fatalf("got %d chunks, want %d", len(session.Chunks), chunks)
+	}
+}
@@ -0,0 +1,236 @@
+// Package chunkgc prunes stale chunked-upload state: both the
+// conf.TempDir/chunks staging directories FsChunkMerge writes while
+// assembling a file, and the per-chunk blobs an abandoned upload leaves
+// referenced in the content-addressed pool (internal/chunk's CASIndex).
+// Before the merge step a session's chunks/<id> directory holds at most
+// the fs store's session.json manifest (with the db store it doesn't
+// exist at all), so a session that's staged but never merged - exactly
+// the "crashed client" case this package exists for - is reliably found
+// only through the session store; the scanner has to sweep by session,
+// not by directory listing, to catch it. The design mirrors docker's
+// fscache prune: a periodic scan removes anything past its TTL, and a
+// disk-usage budget evicts the least-recently-touched sessions first
+// when staging grows too large.
+package chunkgc
+
+import (
+	"context"
+	"os"
+	stdpath "path"
+	"sort"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/chunk"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// Usage summarizes the current state of the chunk-staging area, as
+// returned by GET /api/fs/chunks/usage.
+type Usage struct {
+	TotalBytes    int64     `json:"total_bytes"`
+	SessionCount  int       `json:"session_count"`
+	OldestSession string    `json:"oldest_session,omitempty"`
+	OldestSince   time.Time `json:"oldest_since,omitempty"`
+}
+
+// entry is one upload session, joined against its on-disk chunks/<id>
+// staging directory if one currently exists.
+type entry struct {
+	id        string
+	path      string
+	size      int64
+	lastTouch time.Time
+	aborted   bool
+	session   *chunk.UploadSession
+}
+
+// Scanner enforces retention on conf.TempDir/chunks and the CAS chunk
+// pool those sessions reference.
+type Scanner struct {
+	Store chunk.Store
+	CAS   chunk.CASIndex
+}
+
+// NewScanner returns a Scanner bound to store and cas.
+func NewScanner(store chunk.Store, cas chunk.CASIndex) *Scanner {
+	return &Scanner{Store: store, CAS: cas}
+}
+
+func (s *Scanner) root() string {
+	return stdpath.Join(conf.Conf.TempDir, "chunks")
+}
+
+func (s *Scanner) ttl() time.Duration {
+	seconds := setting.GetInt64(conf.ChunkSessionTTL, 0)
+	if seconds <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (s *Scanner) maxBytes() int64 {
+	return setting.GetInt64(conf.ChunkStagingMaxBytes, 0)
+}
+
+// Run starts the periodic sweep goroutine; it returns once ctx is
+// cancelled.
+func (s *Scanner) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if reclaimed, err := s.Sweep(ctx); err != nil {
+				utils.Log.Warnf("[chunkgc] sweep failed: %v", err)
+			} else if reclaimed > 0 {
+				utils.Log.Infof("[chunkgc] sweep reclaimed %d bytes", reclaimed)
+			}
+		}
+	}
+}
+
+// Sweep runs one collection pass: it deletes directories that are
+// aborted or past the TTL, then - if the staging area still exceeds the
+// configured byte budget - evicts the least-recently-touched sessions
+// until it's back under budget. It returns the total bytes reclaimed.
+func (s *Scanner) Sweep(ctx context.Context) (int64, error) {
+	entries, err := s.list(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var reclaimed int64
+	ttl := s.ttl()
+	now := time.Now()
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.aborted || now.Sub(e.lastTouch) > ttl {
+			reclaimed += s.evict(ctx, e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	entries = kept
+
+	if budget := s.maxBytes(); budget > 0 {
+		var total int64
+		for _, e := range entries {
+			total += e.size
+		}
+		if total > budget {
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].lastTouch.Before(entries[j].lastTouch)
+			})
+			for _, e := range entries {
+				if total <= budget {
+					break
+				}
+				reclaimed += s.evict(ctx, e)
+				total -= e.size
+			}
+		}
+	}
+	return reclaimed, nil
+}
+
+// Usage reports the current size/count of the staging area without
+// modifying anything.
+func (s *Scanner) Usage(ctx context.Context) (Usage, error) {
+	entries, err := s.list(ctx)
+	if err != nil {
+		return Usage{}, err
+	}
+	u := Usage{SessionCount: len(entries)}
+	var oldest *entry
+	for i := range entries {
+		u.TotalBytes += entries[i].size
+		if oldest == nil || entries[i].lastTouch.Before(oldest.lastTouch) {
+			oldest = &entries[i]
+		}
+	}
+	if oldest != nil {
+		u.OldestSession = oldest.id
+		u.OldestSince = oldest.lastTouch
+	}
+	return u, nil
+}
+
+// list enumerates every known upload session - the store, not the
+// chunks/ directory listing, is the source of truth, since a session
+// that's still receiving chunks (or was aborted before ever merging)
+// has no merged file under chunks/<id> yet but still holds CAS
+// references that need to be released once it's swept. Where a
+// chunks/<id> directory does exist (the fs store's manifest, or a merge
+// in progress), its size/mtime feed into the entry too.
+func (s *Scanner) list(ctx context.Context) ([]entry, error) {
+	sessions, err := s.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(sessions))
+	for _, sess := range sessions {
+		path := stdpath.Join(s.root(), sess.ID)
+		size, mtime := dirStat(path)
+		lastTouch := sess.UpdatedAt
+		if mtime.After(lastTouch) {
+			lastTouch = mtime
+		}
+		entries = append(entries, entry{
+			id:        sess.ID,
+			path:      path,
+			size:      size,
+			lastTouch: lastTouch,
+			aborted:   sess.Status == chunk.StatusAborted,
+			session:   sess,
+		})
+	}
+	return entries, nil
+}
+
+func (s *Scanner) evict(ctx context.Context, e entry) int64 {
+	if e.session != nil {
+		chunk.ReleaseChunks(ctx, s.CAS, e.session)
+	}
+	if err := os.RemoveAll(e.path); err != nil {
+		utils.Log.Warnf("[chunkgc] failed to remove %s: %v", e.path, err)
+	}
+	if err := s.Store.Delete(ctx, e.id); err != nil && err != chunk.ErrNotFound {
+		utils.Log.Warnf("[chunkgc] failed to delete session %s: %v", e.id, err)
+	}
+	utils.Log.Infof("[chunkgc] evicted upload session %s (%d bytes)", e.id, e.size)
+	return e.size
+}
+
+// dirStat returns the total size of regular files directly under dir
+// and the most recent modification time among them (falling back to
+// the directory's own mtime if it's empty).
+func dirStat(dir string) (size int64, mtime time.Time) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return 0, time.Time{}
+	}
+	mtime = info.ModTime()
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, mtime
+	}
+	for _, f := range files {
+		fi, err := f.Info()
+		if err != nil {
+			continue
+		}
+		size += fi.Size()
+		if fi.ModTime().After(mtime) {
+			mtime = fi.ModTime()
+		}
+	}
+	return size, mtime
+}
@@ -0,0 +1,115 @@
+package chunkgc
+
+import (
+	"context"
+	"os"
+	stdpath "path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/chunk"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+)
+
+// fakeCAS is a minimal in-memory chunk.CASIndex for exercising
+// Scanner.evict's release calls without touching disk.
+type fakeCAS struct {
+	mu   sync.Mutex
+	refs map[string]int
+}
+
+func newFakeCAS() *fakeCAS { return &fakeCAS{refs: make(map[string]int)} }
+
+func (c *fakeCAS) Path(hash string) string { return hash }
+
+func (c *fakeCAS) Has(ctx context.Context, hash string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refs[hash] > 0, nil
+}
+
+func (c *fakeCAS) Put(ctx context.Context, hash string, size int64, src string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refs[hash]++
+	return nil
+}
+
+func (c *fakeCAS) Retain(ctx context.Context, hash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refs[hash]++
+	return nil
+}
+
+func (c *fakeCAS) Release(ctx context.Context, hash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refs[hash]--
+	return nil
+}
+
+func (c *fakeCAS) refCount(hash string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refs[hash]
+}
+
+// TestScannerSweepReleasesCASRefsForUnmergedSession covers the gap this
+// package exists to close: a session that received chunks but crashed
+// before ever reaching FsChunkMerge has no merged file under chunks/<id>
+// (with the fs store, only its session.json manifest), just a session
+// record and CAS refs. A sweep past its TTL must still release those
+// refs, not just silently drop the session record.
+func TestScannerSweepReleasesCASRefsForUnmergedSession(t *testing.T) {
+	conf.Conf = &conf.Config{TempDir: t.TempDir()}
+	store := chunk.NewFsStore()
+	cas := newFakeCAS()
+	ctx := context.Background()
+
+	const hash = "deadbeef"
+	if err := cas.Put(ctx, hash, 4, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := store.Create(ctx, &chunk.UploadSession{ID: "upload-1", TotalChunks: 1}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := store.PutChunk(ctx, "upload-1", chunk.ChunkState{Index: 0, Hash: hash}); err != nil {
+		t.Fatalf("PutChunk: %v", err)
+	}
+	// Backdate the session past the default TTL instead of waiting on it.
+	session, err := store.Get(ctx, "upload-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	session.UpdatedAt = old
+	// The scanner also reads the mtime of chunks/<id>, where the fs store
+	// keeps the manifest, so age that too.
+	manifest := stdpath.Join(conf.Conf.TempDir, "chunks", "upload-1", "session.json")
+	for _, p := range []string{manifest, stdpath.Dir(manifest)} {
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+	info, err := os.Stat(manifest)
+	if err != nil {
+		t.Fatalf("Stat manifest: %v", err)
+	}
+
+	scanner := NewScanner(store, cas)
+	reclaimed, err := scanner.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if reclaimed != info.Size() {
+		t.Fatalf("reclaimed = %d, want %d (only the session manifest was on disk)", reclaimed, info.Size())
+	}
+	if got := cas.refCount(hash); got != 0 {
+		t.Fatalf("CAS refcount for %s = %d, want 0 after sweep", hash, got)
+	}
+	if _, err := store.Get(ctx, "upload-1"); err != chunk.ErrNotFound {
+		t.Fatalf("session still present after sweep: err = %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package conf
+
+// Setting keys for the chunked-upload subsystem (internal/chunk,
+// internal/chunkgc, internal/fs/chunked.go, server/handles/fsup*.go).
+// Values are read through setting.GetStr/GetInt64 like every other
+// runtime-configurable key, so they can be changed from the admin UI
+// without a restart.
+const (
+	// ChunkSessionTTL is how many seconds an upload session may sit idle
+	// before chunkgc.Scanner sweeps it and releases its CAS chunk
+	// references. Aborted sessions are swept on the next pass regardless
+	// of this TTL. 0 falls back to the scanner's own default.
+	ChunkSessionTTL = "chunk_session_ttl"
+	// ChunkStagingMaxBytes caps the total size of conf.TempDir/chunks
+	// before chunkgc.Scanner starts evicting the least-recently-touched
+	// sessions to bring it back under budget. 0 disables the budget.
+	ChunkStagingMaxBytes = "chunk_staging_max_bytes"
+	// ChunkStagingCompression selects how stageChunks compresses the
+	// merged file it writes under conf.TempDir/chunks before handing it
+	// off to fs.PutDirectly/PutAsTask: "none", "zstd", or "zstd-fast".
+	// Empty or unrecognized values behave like "none".
+	ChunkStagingCompression = "chunk_staging_compression"
+	// ChunkUploadConcurrency caps how many parts PutChunkedAsTask hands a
+	// ParallelPartPutter driver at once. Defaults to 4 when unset or <1.
+	ChunkUploadConcurrency = "chunk_upload_concurrency"
+)
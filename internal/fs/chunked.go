@@ -0,0 +1,87 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// ParallelPartPutter is an optional extension a storage driver can
+// implement when it exposes its own multipart/ranged PUT primitive (S3,
+// OneDrive, 115, Aliyun Drive, ...). PutChunkedAsTask prefers this over
+// staging chunks into one local file and streaming that once, since the
+// driver can instead pull each part straight out of the chunk pool and
+// upload several of them at the same time.
+//
+// No driver in this tree implements PutParts yet - wiring a concrete
+// driver (S3 multipart, OneDrive/Aliyun Drive resumable sessions, ...)
+// is deferred to a follow-up change scoped to that driver's package.
+// Until one opts in, storage.(ParallelPartPutter) never succeeds and
+// PutChunkedAsTask always takes the fallback path below.
+type ParallelPartPutter interface {
+	// PutParts uploads obj's parts concurrently, reading each part i
+	// from reader.PartReader(i), with at most concurrency parts in
+	// flight at once. Implementations are expected to retry a failed
+	// part on their own before giving up on the whole upload.
+	PutParts(ctx context.Context, dstDir model.Obj, obj *model.Object, reader *stream.ChunkedReaderAt, concurrency int) error
+}
+
+// PutChunkedAsTask uploads the chunks behind reader to dir/obj.Name. If
+// the storage resolved for dir implements ParallelPartPutter, its parts
+// are handed over concurrently (bounded by setting.ChunkUploadConcurrency)
+// instead of requiring a fully merged local file first; otherwise this
+// falls back to the ordinary fs.PutAsTask over a single ordered stream,
+// i.e. exactly what merging the chunks locally first would have produced.
+func PutChunkedAsTask(ctx context.Context, dir string, obj *model.Object, reader *stream.ChunkedReaderAt) error {
+	storage, err := GetStorage(dir, &GetStoragesArgs{})
+	if err != nil {
+		return err
+	}
+
+	if ppp, ok := storage.(ParallelPartPutter); ok {
+		dstDir, err := Get(ctx, dir, &GetArgs{NoLog: true})
+		if err != nil {
+			return err
+		}
+		concurrency := int(setting.GetInt64(conf.ChunkUploadConcurrency, 4))
+		if concurrency < 1 {
+			concurrency = 4
+		}
+		return putPartsWithRetry(ctx, ppp, dstDir, obj, reader, concurrency)
+	}
+
+	s := &stream.FileStream{
+		Obj:      obj,
+		Reader:   io.NewSectionReader(reader, 0, reader.Size()),
+		Mimetype: utils.GetMimeType(obj.GetName()),
+	}
+	_, err = PutAsTask(ctx, dir, s)
+	return err
+}
+
+// putPartsWithRetry gives the driver's own PutParts up to two extra
+// whole-call retries (three attempts in all) on top of whatever per-part
+// retry it does internally, since a
+// transient failure partway through a multipart upload is still cheaper
+// to restart than to fall back to a full local merge.
+func putPartsWithRetry(ctx context.Context, ppp ParallelPartPutter, dstDir model.Obj, obj *model.Object, reader *stream.ChunkedReaderAt, concurrency int) error {
+	const maxAttempts = 3
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = ppp.PutParts(ctx, dstDir, obj, reader, concurrency); err == nil {
+			return nil
+		}
+		utils.Log.Warnf("[PutChunkedAsTask] part upload attempt %d/%d failed: %v", attempt, maxAttempts, err)
+		if attempt < maxAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	return fmt.Errorf("parallel part upload failed after %d attempts: %w", maxAttempts, err)
+}
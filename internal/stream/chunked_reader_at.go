@@ -0,0 +1,85 @@
+package stream
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// ChunkedReaderAt presents a sequence of on-disk chunk files as a single
+// io.ReaderAt, so a storage driver capable of multipart/ranged PUT can
+// pull arbitrary byte ranges directly - one part per backing file -
+// without the caller first concatenating them into one local file.
+type ChunkedReaderAt struct {
+	paths []string
+	// offsets[i] is the logical start of paths[i]; offsets[len(paths)]
+	// is the total size.
+	offsets []int64
+}
+
+// NewChunkedReaderAt stats each of paths once to build the cumulative
+// offset table used by ReadAt/PartRange.
+func NewChunkedReaderAt(paths []string) (*ChunkedReaderAt, error) {
+	offsets := make([]int64, len(paths)+1)
+	for i, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		offsets[i+1] = offsets[i] + info.Size()
+	}
+	return &ChunkedReaderAt{paths: paths, offsets: offsets}, nil
+}
+
+// Size returns the total logical size across every part.
+func (r *ChunkedReaderAt) Size() int64 {
+	return r.offsets[len(r.offsets)-1]
+}
+
+// PartCount returns how many underlying files back this reader.
+func (r *ChunkedReaderAt) PartCount() int {
+	return len(r.paths)
+}
+
+// PartRange returns the [start, end) byte range part i occupies in the
+// logical, concatenated file.
+func (r *ChunkedReaderAt) PartRange(i int) (start, end int64) {
+	return r.offsets[i], r.offsets[i+1]
+}
+
+// PartReader opens part i for reading; the caller must close it. This
+// is what a ParallelPartPutter should use to read one part at a time,
+// concurrently with the others.
+func (r *ChunkedReaderAt) PartReader(i int) (*os.File, error) {
+	return os.Open(r.paths[i])
+}
+
+// ReadAt implements io.ReaderAt across every part, for callers that
+// just want a plain byte-range view (e.g. the fallback path in
+// fs.PutChunkedAsTask when no ParallelPartPutter is available).
+func (r *ChunkedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	size := r.Size()
+	if off < 0 || off >= size {
+		return 0, io.EOF
+	}
+	idx := sort.Search(len(r.paths), func(i int) bool { return r.offsets[i+1] > off })
+	n := 0
+	for idx < len(r.paths) && n < len(p) {
+		f, err := os.Open(r.paths[idx])
+		if err != nil {
+			return n, err
+		}
+		partOff := off + int64(n) - r.offsets[idx]
+		m, err := f.ReadAt(p[n:], partOff)
+		f.Close()
+		n += m
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		idx++
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,150 @@
+package handles
+
+import (
+	"io"
+	"os"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/chunk"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/klauspost/compress/zstd"
+)
+
+// mergedStage is the result of merging an upload's chunks into a single
+// staged file, ready to be streamed into fs.PutDirectly/PutAsTask.
+type mergedStage struct {
+	Path           string
+	Compressed     bool
+	PlainSize      int64
+	CompressedSize int64
+	HashMap        map[*utils.HashType]string
+}
+
+// CompressionRatio returns PlainSize/CompressedSize, or 1 when staging
+// wasn't compressed.
+func (m *mergedStage) CompressionRatio() float64 {
+	if !m.Compressed || m.CompressedSize == 0 {
+		return 1
+	}
+	return float64(m.PlainSize) / float64(m.CompressedSize)
+}
+
+// stageChunks concatenates session's chunks (resolved through the CAS
+// pool) into chunkDir, optionally compressing the staged file according
+// to setting.ChunkStagingCompression ("none", "zstd", "zstd-fast"). The
+// plaintext xxh64/CRC64 hashes are always computed on the uncompressed
+// side, so req.Hash verification is unaffected by staging compression.
+func stageChunks(chunkDir string, session *chunk.UploadSession, totalChunks int) (*mergedStage, error) {
+	hasher := utils.NewMultiHasher([]*utils.HashType{utils.XXH64, utils.CRC64})
+	if err := os.MkdirAll(chunkDir, 0755); err != nil {
+		return nil, err
+	}
+
+	mode := setting.GetStr(conf.ChunkStagingCompression, "none")
+	if mode != "zstd" && mode != "zstd-fast" {
+		path := stdpath.Join(chunkDir, "merged")
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		size, err := copyChunksInto(io.MultiWriter(f, hasher), session, totalChunks)
+		if err != nil {
+			return nil, err
+		}
+		return &mergedStage{Path: path, PlainSize: size, HashMap: hasher.GetHashInfo().Export()}, nil
+	}
+
+	path := stdpath.Join(chunkDir, "merged.zst")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	level := zstd.SpeedDefault
+	if mode == "zstd-fast" {
+		level = zstd.SpeedFastest
+	}
+	zw, err := zstd.NewWriter(f, zstd.WithEncoderLevel(level))
+	if err != nil {
+		return nil, err
+	}
+	// hasher sits on the plaintext side of the MultiWriter alongside zw;
+	// only zw's compressed output reaches the staged file.
+	size, err := copyChunksInto(io.MultiWriter(zw, hasher), session, totalChunks)
+	if err != nil {
+		zw.Close()
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	var compressedSize int64
+	if info, err := os.Stat(path); err == nil {
+		compressedSize = info.Size()
+	}
+	return &mergedStage{
+		Path:           path,
+		Compressed:     true,
+		PlainSize:      size,
+		CompressedSize: compressedSize,
+		HashMap:        hasher.GetHashInfo().Export(),
+	}, nil
+}
+
+func copyChunksInto(w io.Writer, session *chunk.UploadSession, totalChunks int) (int64, error) {
+	var total int64
+	for i := 0; i < totalChunks; i++ {
+		chunkPath := casIndex.Path(session.Chunks[i].Hash)
+		f, err := os.Open(chunkPath)
+		if err != nil {
+			return total, err
+		}
+		n, err := io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// zstdFileReader decompresses a staged merged.zst file on the fly so
+// fs.PutDirectly/PutAsTask can stream plaintext bytes directly out of
+// the compressed staging file without a second full-size copy on disk.
+type zstdFileReader struct {
+	f   *os.File
+	dec *zstd.Decoder
+}
+
+func (r *zstdFileReader) Read(p []byte) (int, error) { return r.dec.Read(p) }
+
+// Close releases the decoder before closing the underlying file, as
+// required by the zstd.Decoder API.
+func (r *zstdFileReader) Close() error {
+	r.dec.Close()
+	return r.f.Close()
+}
+
+// openStageReader opens stage.Path for reading, transparently wrapping
+// it in a zstd decoder when the stage was written compressed. Callers
+// always see plaintext bytes regardless of staging mode.
+func openStageReader(stage *mergedStage) (io.ReadCloser, error) {
+	f, err := os.Open(stage.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !stage.Compressed {
+		return f, nil
+	}
+	dec, err := zstd.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &zstdFileReader{f: f, dec: dec}, nil
+}
@@ -0,0 +1,638 @@
+package handles
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// tusResumableVersion is the tus.io protocol version this server speaks.
+const tusResumableVersion = "1.0.0"
+
+// tusExtensions advertised via the Tus-Extension header.
+const tusExtensions = "creation,creation-with-upload,expiration,termination,checksum,concatenation"
+
+// tusSessionTimeout is how long an idle upload may sit before it is
+// considered expired and eligible for termination.
+const tusSessionTimeout = 24 * time.Hour
+
+// tusSession tracks a single tus upload on disk so it can be resumed
+// across server restarts.
+type tusSession struct {
+	ID         string            `json:"id"`
+	UserID     uint              `json:"user_id"`
+	Dir        string            `json:"dir"`
+	Name       string            `json:"name"`
+	Size       int64             `json:"size"`
+	Offset     int64             `json:"offset"`
+	Metadata   map[string]string `json:"metadata"`
+	AsTask     bool              `json:"as_task"`
+	Overwrite  bool              `json:"overwrite"`
+	CreatedAt  time.Time         `json:"created_at"`
+	LastActive time.Time         `json:"last_active"`
+	// Partial and Final implement the Concatenation extension: a partial
+	// upload is never finalized on its own; a final upload is the
+	// concatenation of the partial uploads named in its Upload-Concat header.
+	Partial bool `json:"partial"`
+	Final   bool `json:"final"`
+
+	mu sync.Mutex `json:"-"`
+}
+
+func (s *tusSession) dataPath() string {
+	return stdpath.Join(conf.Conf.TempDir, "tus", s.ID, "data")
+}
+
+func (s *tusSession) manifestPath() string {
+	return stdpath.Join(conf.Conf.TempDir, "tus", s.ID, "session.json")
+}
+
+// tusStore persists tus sessions under conf.TempDir/tus/<id>/session.json
+// and keeps a hot cache in memory so the common path never touches disk
+// for metadata reads.
+type tusStore struct {
+	cache sync.Map // id -> *tusSession
+}
+
+var tusSessions = &tusStore{}
+
+// tusInfraOnce guards the lazy setup in ensureTusInfra.
+var tusInfraOnce sync.Once
+
+// ensureTusInfra reloads persisted sessions from disk and starts the
+// expiry ticker the first time any tus handler runs. This can't be a
+// package init(): init() functions run at process load, before the
+// app's bootstrap sequence sets conf.Conf, so reload() would dereference
+// a nil conf.Conf.TempDir and crash the server on startup (see
+// ensureChunkInfra in fsup.go for the same hazard). By the time an HTTP
+// request reaches one of these handlers conf.Conf is guaranteed to be set.
+func ensureTusInfra() {
+	tusInfraOnce.Do(func() {
+		tusSessions.reload()
+		go func() {
+			ticker := time.NewTicker(10 * time.Minute)
+			defer ticker.Stop()
+			for range ticker.C {
+				tusSessions.expireStale()
+			}
+		}()
+	})
+}
+
+func (st *tusStore) reload() {
+	root := stdpath.Join(conf.Conf.TempDir, "tus")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		s, err := st.load(e.Name())
+		if err != nil {
+			continue
+		}
+		st.cache.Store(s.ID, s)
+	}
+}
+
+func (st *tusStore) load(id string) (*tusSession, error) {
+	data, err := os.ReadFile(stdpath.Join(conf.Conf.TempDir, "tus", id, "session.json"))
+	if err != nil {
+		return nil, err
+	}
+	s := &tusSession{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (st *tusStore) save(s *tusSession) error {
+	if err := os.MkdirAll(stdpath.Join(conf.Conf.TempDir, "tus", s.ID), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.manifestPath(), data, 0644); err != nil {
+		return err
+	}
+	st.cache.Store(s.ID, s)
+	return nil
+}
+
+func (st *tusStore) get(id string) (*tusSession, bool) {
+	if v, ok := st.cache.Load(id); ok {
+		return v.(*tusSession), true
+	}
+	s, err := st.load(id)
+	if err != nil {
+		return nil, false
+	}
+	st.cache.Store(id, s)
+	return s, true
+}
+
+func (st *tusStore) delete(id string) {
+	st.cache.Delete(id)
+	_ = os.RemoveAll(stdpath.Join(conf.Conf.TempDir, "tus", id))
+}
+
+func (st *tusStore) expireStale() {
+	now := time.Now()
+	st.cache.Range(func(key, value any) bool {
+		s := value.(*tusSession)
+		s.mu.Lock()
+		stale := now.Sub(s.LastActive) > tusSessionTimeout
+		s.mu.Unlock()
+		if stale {
+			st.delete(s.ID)
+		}
+		return true
+	})
+}
+
+// checkTusOwnership rejects access to a session belonging to a different
+// user. tusSession carries UserID precisely so HEAD/PATCH/DELETE and
+// concatenation can't be used to probe or tamper with someone else's
+// upload by guessing its ID.
+func checkTusOwnership(c *gin.Context, session *tusSession) bool {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if session.UserID != user.ID {
+		common.ErrorStrResp(c, "upload not found", 404)
+		return false
+	}
+	return true
+}
+
+// parseTusMetadata decodes the Upload-Metadata header, a comma separated
+// list of "key base64Value" pairs.
+func parseTusMetadata(header string) map[string]string {
+	meta := make(map[string]string)
+	if header == "" {
+		return meta
+	}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, " ", 2)
+		key := parts[0]
+		value := ""
+		if len(parts) == 2 {
+			if decoded, err := base64.StdEncoding.DecodeString(parts[1]); err == nil {
+				value = string(decoded)
+			}
+		}
+		meta[key] = value
+	}
+	return meta
+}
+
+// setTusHeaders stamps every tus response with the protocol version.
+// The Tus-Extension list is only sent by FsTusOptions.
+func setTusHeaders(c *gin.Context) {
+	c.Header("Tus-Resumable", tusResumableVersion)
+	c.Header("Tus-Version", tusResumableVersion)
+}
+
+func checkTusResumable(c *gin.Context) bool {
+	if c.Request.Method == http.MethodOptions {
+		return true
+	}
+	if c.GetHeader("Tus-Resumable") != tusResumableVersion {
+		setTusHeaders(c)
+		c.Status(412)
+		return false
+	}
+	return true
+}
+
+// FsTusOptions answers the protocol discovery request (capability probe).
+func FsTusOptions(c *gin.Context) {
+	ensureTusInfra()
+	setTusHeaders(c)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Checksum-Algorithm", "md5,sha1,sha256,crc32")
+	c.Status(204)
+}
+
+// FsTusCreate implements the Creation (and Creation-With-Upload,
+// Concatenation) extensions: it allocates a new upload and, if the
+// request carries a body, writes its first bytes immediately.
+func FsTusCreate(c *gin.Context) {
+	ensureTusInfra()
+	if !checkTusResumable(c) {
+		return
+	}
+	setTusHeaders(c)
+
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+
+	uploadConcat := c.GetHeader("Upload-Concat")
+	if strings.HasPrefix(uploadConcat, "final;") {
+		finishTusConcatenation(c, user, uploadConcat)
+		return
+	}
+
+	path := c.GetHeader("File-Path")
+	metadata := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if path == "" {
+		if name, ok := metadata["filename"]; ok {
+			path = name
+		}
+	}
+	path, err := url.PathUnescape(path)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	path, err = user.JoinPath(path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	dir, name := stdpath.Split(path)
+	if shouldIgnoreSystemFile(name) {
+		common.ErrorStrResp(c, "ignored system file", 403)
+		return
+	}
+
+	var size int64
+	if uploadConcat == "partial" {
+		// Partial uploads may defer their length to the owning final upload.
+		size = 0
+	} else {
+		sizeStr := c.GetHeader("Upload-Length")
+		size, err = strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil {
+			common.ErrorStrResp(c, "Upload-Length is required", 400)
+			return
+		}
+	}
+
+	overwrite := c.GetHeader("Overwrite") != "false"
+	if !overwrite && uploadConcat != "partial" {
+		if res, _ := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); res != nil {
+			common.ErrorStrResp(c, "file exists", 403)
+			return
+		}
+	}
+
+	session := &tusSession{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		Dir:        dir,
+		Name:       name,
+		Size:       size,
+		Metadata:   metadata,
+		AsTask:     c.GetHeader("As-Task") == "true",
+		Overwrite:  overwrite,
+		CreatedAt:  time.Now(),
+		LastActive: time.Now(),
+		Partial:    uploadConcat == "partial",
+	}
+	if err := os.MkdirAll(stdpath.Join(conf.Conf.TempDir, "tus", session.ID), 0755); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if f, err := os.Create(session.dataPath()); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	} else {
+		f.Close()
+	}
+	if err := tusSessions.save(session); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	if c.GetHeader("Content-Type") == "application/offset+octet-stream" && c.Request.ContentLength > 0 {
+		if !appendTusChunk(c, session, 0) {
+			return
+		}
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/fs/tus/%s", session.ID))
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	if session.Partial {
+		c.Header("Upload-Concat", "partial")
+	}
+	c.Status(201)
+}
+
+// FsTusHead reports the current offset so a client can resume.
+func FsTusHead(c *gin.Context) {
+	ensureTusInfra()
+	if !checkTusResumable(c) {
+		return
+	}
+	setTusHeaders(c)
+	session, ok := tusSessions.get(c.Param("id"))
+	if !ok {
+		c.Status(404)
+		return
+	}
+	if !checkTusOwnership(c, session) {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	if session.Partial {
+		c.Header("Upload-Concat", "partial")
+	} else {
+		c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	}
+	c.Status(200)
+}
+
+// FsTusPatch implements the core upload: it appends bytes at Upload-Offset
+// and, once complete, hands the assembled file to fs.PutDirectly/PutAsTask.
+func FsTusPatch(c *gin.Context) {
+	ensureTusInfra()
+	if !checkTusResumable(c) {
+		return
+	}
+	setTusHeaders(c)
+	if c.GetHeader("Content-Type") != "application/offset+octet-stream" {
+		common.ErrorStrResp(c, "unsupported Content-Type", 415)
+		return
+	}
+	session, ok := tusSessions.get(c.Param("id"))
+	if !ok {
+		c.Status(404)
+		return
+	}
+	if !checkTusOwnership(c, session) {
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		common.ErrorStrResp(c, "Upload-Offset is required", 400)
+		return
+	}
+	session.mu.Lock()
+	current := session.Offset
+	session.mu.Unlock()
+	if offset != current {
+		c.Status(409) // Conflict: client and server disagree on the offset
+		return
+	}
+
+	if !appendTusChunk(c, session, offset) {
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+
+	if !session.Partial && session.Size > 0 && session.Offset >= session.Size {
+		if err := finishTusUpload(c.Request.Context(), session); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	}
+	c.Status(204)
+}
+
+// tusChecksumAlgorithms maps the Upload-Checksum algorithm token to the
+// hash types this server already knows how to compute.
+var tusChecksumAlgorithms = map[string]*utils.HashType{
+	"md5":    utils.MD5,
+	"sha1":   utils.SHA1,
+	"sha256": utils.SHA256,
+	"crc32":  utils.CRC32,
+}
+
+// appendTusChunk writes the request body to the session's data file,
+// verifying the Upload-Checksum extension header when present.
+func appendTusChunk(c *gin.Context, session *tusSession, offset int64) bool {
+	f, err := os.OpenFile(session.dataPath(), os.O_WRONLY, 0644)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return false
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		common.ErrorResp(c, err, 500)
+		return false
+	}
+
+	var reader io.Reader = c.Request.Body
+	var hasher *utils.MultiHasher
+	var checksumType *utils.HashType
+	if checksum := c.GetHeader("Upload-Checksum"); checksum != "" {
+		parts := strings.SplitN(checksum, " ", 2)
+		if len(parts) == 2 {
+			if ht, ok := tusChecksumAlgorithms[parts[0]]; ok {
+				checksumType = ht
+				hasher = utils.NewMultiHasher([]*utils.HashType{ht})
+				reader = io.TeeReader(reader, hasher)
+			}
+		}
+	}
+
+	written, err := io.Copy(f, reader)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return false
+	}
+
+	if hasher != nil {
+		parts := strings.SplitN(c.GetHeader("Upload-Checksum"), " ", 2)
+		expected, decodeErr := base64.StdEncoding.DecodeString(parts[1])
+		// Export() hex-encodes its digests (see hashStagedChunk/
+		// verifyAssembledHash), while Upload-Checksum carries the raw
+		// digest base64-encoded - hex-encode it back before comparing,
+		// and look up the one algorithm we actually hashed with instead
+		// of matching against every exported sum.
+		actual, ok := hasher.GetHashInfo().Export()[checksumType]
+		if decodeErr != nil || !ok || actual != hex.EncodeToString(expected) {
+			common.ErrorStrResp(c, "checksum mismatch", 460)
+			return false
+		}
+	}
+
+	session.mu.Lock()
+	session.Offset = offset + written
+	session.LastActive = time.Now()
+	session.mu.Unlock()
+	if err := tusSessions.save(session); err != nil {
+		common.ErrorResp(c, err, 500)
+		return false
+	}
+	return true
+}
+
+// finishTusUpload streams the completed upload into the target storage
+// and tears down the session.
+func finishTusUpload(ctx context.Context, session *tusSession) error {
+	f, err := os.Open(session.dataPath())
+	if err != nil {
+		return err
+	}
+	s := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     session.Name,
+			Size:     session.Size,
+			Modified: time.Now(),
+		},
+		Reader:       f,
+		Mimetype:     utils.GetMimeType(session.Name),
+		WebPutAsTask: session.AsTask,
+	}
+	s.Closers.Add(utils.CloseFunc(func() error {
+		f.Close()
+		tusSessions.delete(session.ID)
+		return nil
+	}))
+
+	bgCtx := context.Background()
+	if session.AsTask {
+		var t task.TaskExtensionInfo
+		t, err = fs.PutAsTask(bgCtx, session.Dir, s)
+		_ = t
+	} else {
+		err = fs.PutDirectly(bgCtx, session.Dir, s, session.Overwrite)
+	}
+	return err
+}
+
+// finishTusConcatenation implements the Concatenation extension: it joins
+// the data files of the named partial uploads, in order, into a single
+// final upload and immediately completes it.
+func finishTusConcatenation(c *gin.Context, user *model.User, uploadConcat string) {
+	idsPart := strings.TrimPrefix(uploadConcat, "final;")
+	var partials []*tusSession
+	var total int64
+	for _, ref := range strings.Fields(idsPart) {
+		id := stdpath.Base(ref)
+		s, ok := tusSessions.get(id)
+		if !ok {
+			common.ErrorStrResp(c, "unknown partial upload "+id, 400)
+			return
+		}
+		if s.UserID != user.ID {
+			common.ErrorStrResp(c, "unknown partial upload "+id, 400)
+			return
+		}
+		if !s.Partial {
+			common.ErrorStrResp(c, id+" is not a partial upload", 400)
+			return
+		}
+		partials = append(partials, s)
+		total += s.Offset
+	}
+
+	path := c.GetHeader("File-Path")
+	path, err := url.PathUnescape(path)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	path, err = user.JoinPath(path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	dir, name := stdpath.Split(path)
+
+	session := &tusSession{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		Dir:        dir,
+		Name:       name,
+		Size:       total,
+		AsTask:     c.GetHeader("As-Task") == "true",
+		Overwrite:  true,
+		Final:      true,
+		CreatedAt:  time.Now(),
+		LastActive: time.Now(),
+	}
+	if err := os.MkdirAll(stdpath.Join(conf.Conf.TempDir, "tus", session.ID), 0755); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	out, err := os.Create(session.dataPath())
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	for _, p := range partials {
+		in, err := os.Open(p.dataPath())
+		if err != nil {
+			out.Close()
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			out.Close()
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	}
+	out.Close()
+	session.Offset = total
+	if err := tusSessions.save(session); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	if err := finishTusUpload(c.Request.Context(), session); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	for _, p := range partials {
+		tusSessions.delete(p.ID)
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/fs/tus/%s", session.ID))
+	c.Status(201)
+}
+
+// FsTusDelete implements the Termination extension: it aborts an
+// in-progress upload and frees its staged data.
+func FsTusDelete(c *gin.Context) {
+	ensureTusInfra()
+	if !checkTusResumable(c) {
+		return
+	}
+	setTusHeaders(c)
+	id := c.Param("id")
+	session, ok := tusSessions.get(id)
+	if !ok {
+		c.Status(404)
+		return
+	}
+	if !checkTusOwnership(c, session) {
+		return
+	}
+	tusSessions.delete(id)
+	c.Status(204)
+}
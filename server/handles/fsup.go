@@ -12,7 +12,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/chunk"
+	"github.com/OpenListTeam/OpenList/v4/internal/chunkgc"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -24,6 +27,65 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// chunkSessions is the durable store backing FsChunkUpload/FsChunkMerge.
+// It prefers the application database and falls back to plain files
+// under conf.TempDir when no database is configured, so chunked uploads
+// resume correctly either way.
+var chunkSessions chunk.Store
+
+// casIndex is the content-addressed blob pool chunks and whole files are
+// deduplicated against (see FsChunkUpload, FsChunkPrecheck and the
+// instant-upload fast path in fsStreamDirect/FsForm).
+var casIndex chunk.CASIndex
+
+// chunkScanner prunes conf.TempDir/chunks of stale and over-budget
+// upload sessions; see FsChunkPrune/FsChunkUsage for the admin surface.
+var chunkScanner *chunkgc.Scanner
+
+// chunkInfraOnce guards the lazy setup in ensureChunkInfra.
+var chunkInfraOnce sync.Once
+
+// ensureChunkInfra picks the upload-session store/CAS index and starts
+// the gc scanner the first time any chunk-upload handler runs. This
+// can't be a package init(): init() functions run at process load,
+// before the app's bootstrap sequence opens the database connection, so
+// checking db.Gorm() there always saw nil and silently pinned every
+// deployment to the filesystem fallback even when a database was
+// configured. By the time an HTTP request reaches one of these
+// handlers the database is guaranteed to be up.
+func ensureChunkInfra() {
+	chunkInfraOnce.Do(func() {
+		if db.Gorm() != nil {
+			chunkSessions = chunk.NewDbStore()
+			casIndex = chunk.NewDbCASIndex()
+			if err := chunk.AutoMigrate(); err != nil {
+				utils.Log.Errorf("[chunk] failed to migrate upload session table: %v", err)
+			}
+			if err := chunk.AutoMigrateCAS(); err != nil {
+				utils.Log.Errorf("[chunk] failed to migrate CAS blob table: %v", err)
+			}
+		} else {
+			chunkSessions = chunk.NewFsStore()
+			casIndex = chunk.NewFsCASIndex()
+		}
+		chunk.Reconcile(context.Background(), chunkSessions, casIndex)
+
+		chunkScanner = chunkgc.NewScanner(chunkSessions, casIndex)
+		go chunkScanner.Run(context.Background(), 5*time.Minute)
+	})
+}
+
+// chunkSessionTTL mirrors chunkgc.Scanner.ttl's default so a session's
+// ExpiresAt (what chunk.Reconcile acts on at startup) agrees with the
+// TTL chunkgc sweeps by at runtime.
+func chunkSessionTTL() time.Duration {
+	seconds := setting.GetInt64(conf.ChunkSessionTTL, 0)
+	if seconds <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(seconds) * time.Second
+}
+
 func getLastModified(c *gin.Context) time.Time {
 	now := time.Now()
 	lastModifiedStr := c.GetHeader("Last-Modified")
@@ -43,6 +105,58 @@ func shouldIgnoreSystemFile(filename string) bool {
 	return false
 }
 
+// tryInstantUpload implements 秒传 ("instant upload"): if the server
+// already holds a complete blob for hash in the CAS pool, the upload is
+// satisfied by copying from there instead of waiting on the client to
+// send the bytes. It writes the response itself and reports whether it
+// did so, so the caller can skip its normal upload path.
+func tryInstantUpload(c *gin.Context, dir, name, hash string, asTask bool, modified time.Time) bool {
+	ensureChunkInfra()
+	ctx := c.Request.Context()
+	ok, err := casIndex.Has(ctx, hash)
+	if err != nil || !ok {
+		return false
+	}
+	blobPath := casIndex.Path(hash)
+	info, err := os.Stat(blobPath)
+	if err != nil {
+		return false
+	}
+	blob, err := os.Open(blobPath)
+	if err != nil {
+		return false
+	}
+	s := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     info.Size(),
+			Modified: modified,
+			HashInfo: utils.NewHashInfoByMap(map[*utils.HashType]string{utils.SHA256: hash}),
+		},
+		Reader:       blob,
+		Mimetype:     utils.GetMimeType(name),
+		WebPutAsTask: asTask,
+	}
+	s.Closers.Add(utils.CloseFunc(blob.Close))
+
+	var t task.TaskExtensionInfo
+	if asTask {
+		t, err = fs.PutAsTask(ctx, dir, s)
+	} else {
+		err = fs.PutDirectly(ctx, dir, s, true)
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return true
+	}
+	if t == nil {
+		common.SuccessResp(c, gin.H{"instant": true})
+		return true
+	}
+	common.SuccessResp(c, gin.H{"instant": true, "task": getTaskInfo(t)})
+	return true
+}
+
 // StreamUploadSession manages a chunked stream upload session
 type StreamUploadSession struct {
 	pipeWriter *io.PipeWriter
@@ -120,6 +234,7 @@ func FsStream(c *gin.Context) {
 
 // fsStreamChunked handles chunked stream upload with Content-Range
 func fsStreamChunked(c *gin.Context, contentRange string) {
+	ensureChunkInfra()
 	// Parse Content-Range: bytes start-end/total
 	start, _, total, err := parseContentRange(contentRange)
 	if err != nil {
@@ -170,6 +285,25 @@ func fsStreamChunked(c *gin.Context, contentRange string) {
 			done:       make(chan error, 1),
 			lastActive: time.Now(),
 		}
+		// Persist the durable record before admitting the in-memory
+		// session: if this fails, the upload must not proceed as if it
+		// could survive a restart, since that's the entire point of
+		// backing FsStream with chunkSessions in the first place.
+		if err := chunkSessions.Create(c.Request.Context(), &chunk.UploadSession{
+			ID:          sessionKey,
+			UserID:      user.ID,
+			Dir:         dir,
+			Name:        name,
+			TotalSize:   total,
+			TotalChunks: 1,
+			AsTask:      false,
+			Overwrite:   overwrite,
+			Chunks:      make(map[int]chunk.ChunkState),
+			ExpiresAt:   time.Now().Add(chunkSessionTTL()),
+		}); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
 		streamUploadSessions.Store(sessionKey, session)
 
 		// Get mimetype
@@ -213,6 +347,7 @@ func fsStreamChunked(c *gin.Context, contentRange string) {
 	if err != nil {
 		session.pipeWriter.CloseWithError(err)
 		streamUploadSessions.Delete(sessionKey)
+		_ = chunkSessions.Delete(c.Request.Context(), sessionKey)
 		common.ErrorResp(c, err, 500)
 		return
 	}
@@ -221,6 +356,17 @@ func fsStreamChunked(c *gin.Context, contentRange string) {
 	session.received += written
 	currentReceived := session.received
 	session.mu.Unlock()
+	if _, err := chunkSessions.PutChunk(c.Request.Context(), sessionKey, chunk.ChunkState{
+		Index:      0,
+		Size:       currentReceived,
+		ReceivedAt: time.Now(),
+	}); err != nil {
+		// The upload itself is already streaming to storage through the
+		// pipe goroutine above; a failure to record progress only hurts
+		// resumability bookkeeping, not this request, so log rather than
+		// aborting an otherwise-healthy upload.
+		utils.Log.Errorf("[FsStream] failed to persist chunk progress for %s: %v", sessionKey, err)
+	}
 
 	// Check if this is the last chunk
 	if currentReceived >= total {
@@ -230,6 +376,7 @@ func fsStreamChunked(c *gin.Context, contentRange string) {
 		// Wait for upload to complete
 		uploadErr := <-session.done
 		streamUploadSessions.Delete(sessionKey)
+		_ = chunkSessions.Delete(c.Request.Context(), sessionKey)
 
 		if uploadErr != nil {
 			common.ErrorResp(c, uploadErr, 500)
@@ -294,6 +441,11 @@ func fsStreamDirect(c *gin.Context) {
 	if sha256 := c.GetHeader("X-File-Sha256"); sha256 != "" {
 		h[utils.SHA256] = sha256
 	}
+	if sha256, ok := h[utils.SHA256]; ok {
+		if tryInstantUpload(c, dir, name, sha256, asTask, getLastModified(c)) {
+			return
+		}
+	}
 	mimetype := c.GetHeader("Content-Type")
 	if len(mimetype) == 0 {
 		mimetype = utils.GetMimeType(name)
@@ -391,6 +543,11 @@ func FsForm(c *gin.Context) {
 	if sha256 := c.GetHeader("X-File-Sha256"); sha256 != "" {
 		h[utils.SHA256] = sha256
 	}
+	if sha256, ok := h[utils.SHA256]; ok {
+		if tryInstantUpload(c, dir, name, sha256, asTask, getLastModified(c)) {
+			return
+		}
+	}
 	mimetype := file.Header.Get("Content-Type")
 	if len(mimetype) == 0 {
 		mimetype = utils.GetMimeType(name)
@@ -430,6 +587,7 @@ func FsForm(c *gin.Context) {
 
 // FsChunkUpload handles uploading a single chunk of a large file
 func FsChunkUpload(c *gin.Context) {
+	ensureChunkInfra()
 	uploadId := c.Query("upload_id")
 	indexStr := c.Query("index")
 	if uploadId == "" || indexStr == "" {
@@ -437,7 +595,8 @@ func FsChunkUpload(c *gin.Context) {
 		return
 	}
 
-	if _, err := strconv.Atoi(indexStr); err != nil {
+	index, err := strconv.Atoi(indexStr)
+	if err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
@@ -448,56 +607,224 @@ func FsChunkUpload(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-
-	// Create chunk directory
-	chunkDir := stdpath.Join(conf.Conf.TempDir, "chunks", uploadId)
-	if err := os.MkdirAll(chunkDir, 0755); err != nil {
+	src, err := file.Open()
+	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
+	defer src.Close()
 
-	// Save chunk to file
-	chunkPath := stdpath.Join(chunkDir, indexStr)
 	// Get CRC32 from header
 	expectedCRC32 := c.GetHeader("X-Chunk-CRC32")
 
-	// Save the uploaded file temporarily
-	if err := c.SaveUploadedFile(file, chunkPath); err != nil {
+	// Stage the chunk, then compute both checksums in one read of the
+	// staged file: CRC32 for the existing fast client-side verification,
+	// and the strong content hash that keys the CAS pool.
+	staged, size, err := chunk.CopyIntoStaging(src)
+	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
-
-	// Always calculate CRC32 of the saved chunk for verification and response
-	f, err := os.Open(chunkPath)
+	actualCRC32, contentHash, err := hashStagedChunk(staged)
 	if err != nil {
+		os.Remove(staged)
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	defer f.Close()
 
-	actualCRC32, err := utils.HashReader(utils.CRC32, f)
-	if err != nil {
-		os.Remove(chunkPath) // Clean up
+	// Verify CRC32 if provided
+	if expectedCRC32 != "" && actualCRC32 != expectedCRC32 {
+		os.Remove(staged)
+		common.ErrorStrResp(c, fmt.Sprintf("chunk CRC32 mismatch: client=%s, server=%s", expectedCRC32, actualCRC32), 400)
+		return
+	}
+
+	// Adopt the staged file into the content-addressed pool: if another
+	// upload already has identical bytes, the staged copy is dropped
+	// and we just reference the existing blob.
+	if err := casIndex.Put(c.Request.Context(), contentHash, size, staged); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
 
-	// Verify CRC32 if provided
-	if expectedCRC32 != "" {
-		if actualCRC32 != expectedCRC32 {
-			os.Remove(chunkPath) // Clean up
-			common.ErrorStrResp(c, fmt.Sprintf("chunk CRC32 mismatch: client=%s, server=%s", expectedCRC32, actualCRC32), 400)
+	if err := recordChunk(c, uploadId, index, actualCRC32, contentHash, size); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"crc32": actualCRC32,
+		"hash":  contentHash,
+	})
+}
+
+// verifyAssembledHash re-reads every part behind reader, in order, and
+// compares its xxh64 against want. The local-merge path gets this check
+// for free from stageChunks' hasher, which sees every byte as it's
+// written; the ParallelPartPutter path hands parts straight to the
+// driver's own PutParts instead, so without this pass a corrupted CAS
+// blob (or a hash collision between unrelated uploads) would reach
+// storage unnoticed. want must be non-empty; callers skip this when the
+// client didn't supply a hash.
+func verifyAssembledHash(reader *stream.ChunkedReaderAt, want string) error {
+	hasher := utils.NewMultiHasher([]*utils.HashType{utils.XXH64})
+	for i := 0; i < reader.PartCount(); i++ {
+		f, err := reader.PartReader(i)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(hasher, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for ht, sum := range hasher.GetHashInfo().Export() {
+		if ht.Name == "xxh64" && sum != want {
+			return fmt.Errorf("hash mismatch: client=%s, server=%s", want, sum)
+		}
+	}
+	return nil
+}
+
+// abortChunkMerge tears down a session whose merge failed irrecoverably
+// (hash mismatch or a storage write error): it releases the session's
+// CAS refs and removes its staged chunks immediately instead of leaving
+// them held until the chunkgc TTL sweep, then drops the session so the
+// client sees a clean failure instead of an upload_id stuck merging.
+func abortChunkMerge(ctx context.Context, chunkDir string, session *chunk.UploadSession) {
+	chunk.ReleaseChunks(ctx, casIndex, session)
+	os.RemoveAll(chunkDir)
+	_ = chunkSessions.Delete(ctx, session.ID)
+}
+
+// hashStagedChunk computes the CRC32 (legacy quick check) and CAS
+// content hash of a staged chunk in a single read.
+func hashStagedChunk(path string) (crc32, contentHash string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	hasher := utils.NewMultiHasher([]*utils.HashType{utils.CRC32, chunk.CASHash})
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", "", err
+	}
+	for ht, sum := range hasher.GetHashInfo().Export() {
+		switch ht {
+		case utils.CRC32:
+			crc32 = sum
+		case chunk.CASHash:
+			contentHash = sum
+		}
+	}
+	return crc32, contentHash, nil
+}
+
+// recordChunk upserts the upload session for uploadId and records the
+// chunk that was just received, so the session store always reflects
+// what's actually in the CAS pool.
+func recordChunk(c *gin.Context, uploadId string, index int, crc32, contentHash string, size int64) error {
+	ctx := c.Request.Context()
+	if _, err := chunkSessions.Get(ctx, uploadId); err != nil {
+		if err != chunk.ErrNotFound {
+			return err
+		}
+		user := ctx.Value(conf.UserKey).(*model.User)
+		session := &chunk.UploadSession{
+			ID:        uploadId,
+			UserID:    user.ID,
+			Chunks:    make(map[int]chunk.ChunkState),
+			ExpiresAt: time.Now().Add(chunkSessionTTL()),
+		}
+		if err := chunkSessions.Create(ctx, session); err != nil {
+			return err
+		}
+	}
+	_, err := chunkSessions.PutChunk(ctx, uploadId, chunk.ChunkState{
+		Index:      index,
+		Size:       size,
+		CRC32:      crc32,
+		Hash:       contentHash,
+		ReceivedAt: time.Now(),
+	})
+	return err
+}
+
+// FsChunkPrecheck lets a client ask, before uploading anything, which of
+// its chunks the server already holds in the CAS pool by content hash -
+// those can be skipped entirely (秒传 for individual chunks).
+func FsChunkPrecheck(c *gin.Context) {
+	ensureChunkInfra()
+	var req struct {
+		UploadId string `json:"upload_id"`
+		Chunks   []struct {
+			Index int    `json:"index"`
+			Size  int64  `json:"size"`
+			Hash  string `json:"hash"`
+		} `json:"chunks"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.UploadId == "" {
+		common.ErrorStrResp(c, "upload_id is required", 400)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := chunkSessions.Get(ctx, req.UploadId); err != nil {
+		if err != chunk.ErrNotFound {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		user := ctx.Value(conf.UserKey).(*model.User)
+		if err := chunkSessions.Create(ctx, &chunk.UploadSession{
+			ID:        req.UploadId,
+			UserID:    user.ID,
+			Chunks:    make(map[int]chunk.ChunkState),
+			ExpiresAt: time.Now().Add(chunkSessionTTL()),
+		}); err != nil {
+			common.ErrorResp(c, err, 500)
 			return
 		}
 	}
 
+	present := make([]int, 0, len(req.Chunks))
+	for _, ch := range req.Chunks {
+		ok, err := casIndex.Has(ctx, ch.Hash)
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		if !ok {
+			continue
+		}
+		if err := casIndex.Retain(ctx, ch.Hash); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		if _, err := chunkSessions.PutChunk(ctx, req.UploadId, chunk.ChunkState{
+			Index:      ch.Index,
+			Size:       ch.Size,
+			Hash:       ch.Hash,
+			ReceivedAt: time.Now(),
+		}); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		present = append(present, ch.Index)
+	}
+
 	common.SuccessResp(c, gin.H{
-		"crc32": actualCRC32,
+		"present_chunks": present,
 	})
 }
 
-// FsChunkMerge merges all chunks into a single file and uploads it
+// FsChunkMerge merges all chunks into a single file and uploads it.
 func FsChunkMerge(c *gin.Context) {
+	ensureChunkInfra()
 	var req struct {
 		UploadId     string `json:"upload_id"`
 		Path         string `json:"path"`
@@ -530,13 +857,27 @@ func FsChunkMerge(c *gin.Context) {
 
 	chunkDir := stdpath.Join(conf.Conf.TempDir, "chunks", req.UploadId)
 
-	// Check if all chunks exist (quick check, no heavy I/O)
-	for i := 0; i < req.TotalChunks; i++ {
-		chunkPath := stdpath.Join(chunkDir, strconv.Itoa(i))
-		if _, err := os.Stat(chunkPath); os.IsNotExist(err) {
-			common.ErrorStrResp(c, "chunk "+strconv.Itoa(i)+" not found", 400)
-			return
-		}
+	// The session store is the source of truth for which chunks actually
+	// landed; it's what FsUploadStatus reports against too.
+	session, err := chunkSessions.Get(c.Request.Context(), req.UploadId)
+	if err != nil {
+		common.ErrorStrResp(c, "upload session not found", 400)
+		return
+	}
+	session.Dir, session.Name = stdpath.Split(path)
+	session.TotalChunks = req.TotalChunks
+	session.AsTask = req.AsTask
+	session.Overwrite = req.Overwrite
+	if req.Hash != "" {
+		session.Hashes = map[string]string{"xxh64": req.Hash}
+	}
+	if missing := session.MissingChunks(); len(missing) > 0 {
+		common.ErrorStrResp(c, fmt.Sprintf("chunk %d not found", missing[0]), 400)
+		return
+	}
+	if err := chunkSessions.SetStatus(c.Request.Context(), req.UploadId, chunk.StatusMerging); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
 	}
 
 	dir, name := stdpath.Split(path)
@@ -544,6 +885,7 @@ func FsChunkMerge(c *gin.Context) {
 	// Check if system file should be ignored
 	if shouldIgnoreSystemFile(name) {
 		os.RemoveAll(chunkDir)
+		_ = chunkSessions.Delete(c.Request.Context(), req.UploadId)
 		common.ErrorStrResp(c, errs.IgnoredSystemFile.Error(), 403)
 		return
 	}
@@ -553,6 +895,87 @@ func FsChunkMerge(c *gin.Context) {
 		lastModified = time.UnixMilli(req.LastModified)
 	}
 
+	// Chunks already live in the CAS pool keyed by hash; if the resolved
+	// storage can take parts directly (S3 multipart, OneDrive/Aliyun
+	// Drive resumable sessions, ...) skip the local merge+single-stream
+	// upload entirely and let it pull parts concurrently instead. No
+	// driver in this tree implements fs.ParallelPartPutter yet (see its
+	// doc comment), so this branch is currently always skipped in favor
+	// of the sequential-merge path below.
+	if storage, sErr := fs.GetStorage(path, &fs.GetStoragesArgs{}); sErr == nil {
+		if _, ok := storage.(fs.ParallelPartPutter); ok {
+			paths := make([]string, req.TotalChunks)
+			for i := 0; i < req.TotalChunks; i++ {
+				paths[i] = casIndex.Path(session.Chunks[i].Hash)
+			}
+			reader, rErr := stream.NewChunkedReaderAt(paths)
+			if rErr != nil {
+				common.ErrorResp(c, rErr, 500)
+				return
+			}
+			h := make(map[*utils.HashType]string)
+			if req.Hash != "" {
+				h[utils.XXH64] = req.Hash
+			}
+			obj := &model.Object{
+				Name:     name,
+				Size:     reader.Size(),
+				Modified: lastModified,
+				HashInfo: utils.NewHashInfoByMap(h),
+			}
+
+			if req.AsTask {
+				taskId := fmt.Sprintf("merge-%s", req.UploadId)
+				go func() {
+					ctx := context.Background()
+					if req.Hash != "" {
+						if err := verifyAssembledHash(reader, req.Hash); err != nil {
+							utils.Log.Errorf("[ChunkMerge] %v", err)
+							abortChunkMerge(ctx, chunkDir, session)
+							return
+						}
+					}
+					if err := fs.PutChunkedAsTask(ctx, dir, obj, reader); err != nil {
+						utils.Log.Errorf("[ChunkMerge] parallel part upload failed: %v", err)
+						abortChunkMerge(ctx, chunkDir, session)
+						return
+					}
+					chunk.ReleaseChunks(ctx, casIndex, session)
+					os.RemoveAll(chunkDir)
+					_ = chunkSessions.Delete(ctx, req.UploadId)
+					utils.Log.Infof("[ChunkMerge] parallel part upload complete for %s", path)
+				}()
+				common.SuccessResp(c, gin.H{
+					"task": gin.H{
+						"id":      taskId,
+						"status":  "processing",
+						"message": "Merge started in background. Check Tasks page for progress.",
+					},
+					"parallel_upload": true,
+				})
+				return
+			}
+
+			if req.Hash != "" {
+				if err := verifyAssembledHash(reader, req.Hash); err != nil {
+					abortChunkMerge(c.Request.Context(), chunkDir, session)
+					common.ErrorStrResp(c, err.Error(), 400)
+					return
+				}
+			}
+			if err := fs.PutChunkedAsTask(c.Request.Context(), dir, obj, reader); err != nil {
+				abortChunkMerge(c.Request.Context(), chunkDir, session)
+				common.ErrorResp(c, err, 500)
+				return
+			}
+			chunk.ReleaseChunks(c.Request.Context(), casIndex, session)
+			os.RemoveAll(chunkDir)
+			_ = chunkSessions.Delete(c.Request.Context(), req.UploadId)
+			common.SuccessResp(c, gin.H{"parallel_upload": true})
+			return
+		}
+	}
+
 	// For as_task=true (large files), immediately return and process in background
 	if req.AsTask {
 		// Generate a simple task ID for tracking
@@ -562,43 +985,15 @@ func FsChunkMerge(c *gin.Context) {
 		go func() {
 			utils.Log.Infof("[ChunkMerge] Starting background merge for %s", path)
 
-			// Create merged file
-			mergedPath := stdpath.Join(chunkDir, "merged")
-			mergedFile, err := os.Create(mergedPath)
+			stage, err := stageChunks(chunkDir, session, req.TotalChunks)
 			if err != nil {
-				utils.Log.Errorf("[ChunkMerge] Failed to create merged file: %v", err)
+				utils.Log.Errorf("[ChunkMerge] Failed to stage chunks: %v", err)
 				return
 			}
 
-			// Merge all chunks while computing hash
-			var totalSize int64
-			hasher := utils.NewMultiHasher([]*utils.HashType{utils.XXH64, utils.CRC64})
-			multiWriter := io.MultiWriter(mergedFile, hasher)
-			for i := 0; i < req.TotalChunks; i++ {
-				chunkPath := stdpath.Join(chunkDir, strconv.Itoa(i))
-				chunk, err := os.Open(chunkPath)
-				if err != nil {
-					mergedFile.Close()
-					utils.Log.Errorf("[ChunkMerge] Failed to open chunk %d: %v", i, err)
-					return
-				}
-				n, err := io.Copy(multiWriter, chunk)
-				chunk.Close()
-				if err != nil {
-					mergedFile.Close()
-					utils.Log.Errorf("[ChunkMerge] Failed to copy chunk %d: %v", i, err)
-					return
-				}
-				totalSize += n
-			}
-			mergedFile.Close()
-
-			hashInfo := hasher.GetHashInfo()
-			hashMap := hashInfo.Export()
-
 			// Verify client provided hash (xxHash64)
 			if req.Hash != "" {
-				for ht, hashValue := range hashMap {
+				for ht, hashValue := range stage.HashMap {
 					if ht.Name == "xxh64" && hashValue != req.Hash {
 						os.RemoveAll(chunkDir)
 						utils.Log.Errorf("[ChunkMerge] Hash mismatch: Client=%s, Server=%s", req.Hash, hashValue)
@@ -607,10 +1002,12 @@ func FsChunkMerge(c *gin.Context) {
 				}
 			}
 
-			utils.Log.Infof("[ChunkMerge] Merge complete. Size: %d bytes. Uploading to storage...", totalSize)
+			utils.Log.Infof("[ChunkMerge] Merge complete. Size: %d bytes (ratio %.2fx). Uploading to storage...",
+				stage.PlainSize, stage.CompressionRatio())
 
-			// Open merged file for upload
-			mergedReader, err := os.Open(mergedPath)
+			// Open merged file for upload, transparently decompressing
+			// if it was staged with ChunkStagingCompression.
+			mergedReader, err := openStageReader(stage)
 			if err != nil {
 				utils.Log.Errorf("[ChunkMerge] Failed to open merged file: %v", err)
 				return
@@ -619,7 +1016,7 @@ func FsChunkMerge(c *gin.Context) {
 			s := &stream.FileStream{
 				Obj: &model.Object{
 					Name:     name,
-					Size:     totalSize,
+					Size:     stage.PlainSize,
 					Modified: lastModified,
 				},
 				Reader:       mergedReader,
@@ -639,6 +1036,8 @@ func FsChunkMerge(c *gin.Context) {
 				utils.Log.Errorf("[ChunkMerge] Failed to put as task: %v", err)
 				return
 			}
+			chunk.ReleaseChunks(ctx, casIndex, session)
+			_ = chunkSessions.Delete(ctx, req.UploadId)
 			utils.Log.Infof("[ChunkMerge] Successfully queued upload task for %s", path)
 		}()
 
@@ -654,40 +1053,14 @@ func FsChunkMerge(c *gin.Context) {
 	}
 
 	// For as_task=false (small files or direct upload), use synchronous logic
-	mergedPath := stdpath.Join(chunkDir, "merged")
-	mergedFile, err := os.Create(mergedPath)
+	stage, err := stageChunks(chunkDir, session, req.TotalChunks)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
-
-	// Merge all chunks while computing hash
-	var totalSize int64
-	hasher := utils.NewMultiHasher([]*utils.HashType{utils.XXH64, utils.CRC64})
-	multiWriter := io.MultiWriter(mergedFile, hasher)
-	for i := 0; i < req.TotalChunks; i++ {
-		chunkPath := stdpath.Join(chunkDir, strconv.Itoa(i))
-		chunk, err := os.Open(chunkPath)
-		if err != nil {
-			mergedFile.Close()
-			common.ErrorResp(c, err, 500)
-			return
-		}
-		n, err := io.Copy(multiWriter, chunk)
-		chunk.Close()
-		if err != nil {
-			mergedFile.Close()
-			common.ErrorResp(c, err, 500)
-			return
-		}
-		totalSize += n
-	}
-	mergedFile.Close()
-	hashInfo := hasher.GetHashInfo()
-	hashMap := hashInfo.Export()
 	// Prepare hash map for response
 	hashResponse := make(map[string]string)
-	for ht, hashValue := range hashMap {
+	for ht, hashValue := range stage.HashMap {
 		hashResponse[ht.Name] = hashValue
 	}
 
@@ -696,15 +1069,16 @@ func FsChunkMerge(c *gin.Context) {
 		if serverHash, ok := hashResponse["xxh64"]; ok {
 			if serverHash != req.Hash {
 				// Hash mismatch!
-				os.Remove(mergedPath)
+				os.Remove(stage.Path)
 				common.ErrorStrResp(c, fmt.Sprintf("Hash mismatch: Client=%s, Server=%s", req.Hash, serverHash), 400)
 				return
 			}
 		}
 	}
 
-	// Open merged file for upload
-	mergedReader, err := os.Open(mergedPath)
+	// Open merged file for upload, transparently decompressing if it
+	// was staged with ChunkStagingCompression.
+	mergedReader, err := openStageReader(stage)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -713,7 +1087,7 @@ func FsChunkMerge(c *gin.Context) {
 	s := &stream.FileStream{
 		Obj: &model.Object{
 			Name:     name,
-			Size:     totalSize,
+			Size:     stage.PlainSize,
 			Modified: lastModified,
 		},
 		Reader:       mergedReader,
@@ -734,8 +1108,94 @@ func FsChunkMerge(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
+	chunk.ReleaseChunks(c.Request.Context(), casIndex, session)
+	_ = chunkSessions.Delete(c.Request.Context(), req.UploadId)
+
+	common.SuccessResp(c, gin.H{
+		"hash":              hashResponse,
+		"compression_ratio": stage.CompressionRatio(),
+	})
+}
+
+// FsUploadStatus reports how many chunks an in-progress upload has
+// received and which indexes in [0, total_chunks) are still missing.
+// FsChunkUpload and FsChunkPrecheck create sessions without a chunk
+// total, so for those sessions total_chunks is 0, missing_chunks is
+// empty, and received_chunks is the only progress reported.
+func FsUploadStatus(c *gin.Context) {
+	ensureChunkInfra()
+	uploadId := c.Query("upload_id")
+	if uploadId == "" {
+		common.ErrorStrResp(c, "upload_id is required", 400)
+		return
+	}
+	session, err := chunkSessions.Get(c.Request.Context(), uploadId)
+	if err != nil {
+		if err == chunk.ErrNotFound {
+			common.ErrorStrResp(c, "upload session not found", 404)
+			return
+		}
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"status":          session.Status,
+		"total_chunks":    session.TotalChunks,
+		"received_chunks": len(session.Chunks),
+		"missing_chunks":  session.MissingChunks(),
+	})
+}
+
+// FsUploadAbort cancels an in-progress chunked upload and removes its
+// staged chunks.
+func FsUploadAbort(c *gin.Context) {
+	ensureChunkInfra()
+	uploadId := c.Query("upload_id")
+	if uploadId == "" {
+		common.ErrorStrResp(c, "upload_id is required", 400)
+		return
+	}
+	session, err := chunkSessions.Get(c.Request.Context(), uploadId)
+	if err != nil {
+		if err == chunk.ErrNotFound {
+			common.ErrorStrResp(c, "upload session not found", 404)
+			return
+		}
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	chunk.ReleaseChunks(c.Request.Context(), casIndex, session)
+	os.RemoveAll(stdpath.Join(conf.Conf.TempDir, "chunks", uploadId))
+	if err := chunkSessions.Delete(c.Request.Context(), uploadId); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
 
+// FsChunkPrune forces an immediate sweep of the chunk-staging area and
+// reports how many bytes it reclaimed. Intended for admins dealing with
+// a runaway temp directory without waiting for the next periodic sweep.
+func FsChunkPrune(c *gin.Context) {
+	ensureChunkInfra()
+	reclaimed, err := chunkScanner.Sweep(c.Request.Context())
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
 	common.SuccessResp(c, gin.H{
-		"hash": hashResponse,
+		"reclaimed_bytes": reclaimed,
 	})
 }
+
+// FsChunkUsage reports the current size of the chunk-staging area so
+// operators can alarm on runaway temp usage before it becomes a problem.
+func FsChunkUsage(c *gin.Context) {
+	ensureChunkInfra()
+	usage, err := chunkScanner.Usage(c.Request.Context())
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, usage)
+}